@@ -0,0 +1,202 @@
+/*
+Waituntil is a command that waits (sleeps) until some specific time,
+or technically until it is that time or later. It makes some attempt
+to deal with the system time shifting out from underneath it, but it
+doesn't try too hard here.
+
+usage: waituntil [-v] <WHEN>
+
+-v reports the target time waituntil will (try to) wait for.
+
+<WHEN> has two forms. The simple form is HH:MM[:SS], with HH in 24
+hour time. If HH:MM is in the past, waituntil assumes that you mean
+that time tomorrow.
+
+The full form is YYYY-MM-DD HH:MM[:SS]. You can omit YYYY and MM to
+mean the current year and month, and you can omit the time of day (in
+which case it's taken as midnight).  If this time is in the past,
+waituntil exits immediately.
+
+<WHEN> can also be a relative duration from now, such as "90m",
+"2h30m", or "45s" (anything time.ParseDuration() accepts), or the
+more casual "7d", "3days", "2hours", "15mins", or "30secs".
+
+Finally, <WHEN> can be a Unix timestamp (seconds since the epoch, or
+milliseconds if the number is large enough to not plausibly be
+seconds), or a full RFC3339 / ISO-8601 timestamp such as
+"2025-01-02T15:04:05Z" or "2025-01-02T15:04:05-05:00", or a natural
+language expression such as "tomorrow 5pm", "next friday 14:00",
+"in 2 hours", or "monday 9am". If a natural language expression
+resolves to a time in the past, waituntil exits immediately (the
+same as for the full form).
+
+While waiting, SIGHUP makes waituntil immediately re-check the clock
+against the target time, instead of waiting out its current sleep
+interval. SIGINT and SIGTERM make it exit right away, with an exit
+status that is distinguishable from reaching the target time normally.
+
+If the arguments contain "--", everything after it is a command (and
+its arguments) to run once the target time is reached, in place of
+waituntil itself; waituntil's own exit status then becomes the
+command's exit status. -exit-on-past makes waituntil fail instead of
+running the command immediately when the target time is already past.
+-jitter <dur> adds a random delay of up to <dur> before running the
+command, which is useful for spreading out a fan-out of cron-like
+jobs that would otherwise all fire at once.
+
+The time parsing and the wait loop are also available as a library,
+in github.com/siebenmann/waituntil/pkg/waituntil, for other Go
+programs that want the same flexibility.
+
+Author: Chris Siebenmann
+https://github.com/siebenmann/waituntil
+
+Copyright: GPL v3
+*/
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"math/rand"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/siebenmann/waituntil/pkg/waituntil"
+)
+
+// Exit statuses from waitAndReport. exitReachedTarget is what a
+// normal, uninterrupted run returns; exitInterrupted is returned if
+// we were cut short by SIGINT or SIGTERM, so callers can tell the
+// difference.
+const (
+	exitReachedTarget = 0
+	exitInterrupted   = 1
+)
+
+// Split "-- cmd args..." off the end of the argument list, if present,
+// so it doesn't get fed to the flag package (which would choke on it).
+func splitExecArgs(args []string) (waitArgs, cmdArgs []string) {
+	for i, a := range args {
+		if a == "--" {
+			return args[:i], args[i+1:]
+		}
+	}
+	return args, nil
+}
+
+func main() {
+	var tstr string
+	var verbose = flag.Bool("v", false, "be verbose about when we're waiting for")
+	var exitOnPast = flag.Bool("exit-on-past", false, "with -- cmd, fail instead of running cmd immediately if the target time is already past")
+	var jitter = flag.Duration("jitter", 0, "with -- cmd, sleep a random extra delay up to this long before running cmd")
+
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "waituntil [-v] HH:MM[:SS]|duration [-- cmd args...]\n")
+		flag.PrintDefaults()
+	}
+	waitArgs, cmdArgs := splitExecArgs(os.Args[1:])
+	flag.CommandLine.Parse(waitArgs)
+	if flag.NArg() == 0 {
+		fmt.Fprintf(os.Stderr, "%s: no target time given\n", os.Args[0])
+		return
+	}
+
+	tstr = strings.Join(flag.Args(), " ")
+	tgt, e := waituntil.Parse(tstr, time.Now())
+	if e != nil {
+		fmt.Fprintf(os.Stderr, "%s: cannot parse target time\n", os.Args[0])
+		return
+	}
+
+	if *verbose {
+		fmt.Printf("until %s\n", tgt)
+	}
+
+	if len(cmdArgs) == 0 {
+		os.Exit(waitAndReport(tgt, *verbose))
+	}
+
+	if tgt.Before(time.Now()) {
+		if *exitOnPast {
+			fmt.Fprintf(os.Stderr, "%s: target time is already past\n", os.Args[0])
+			os.Exit(1)
+		}
+	} else if rc := waitAndReport(tgt, *verbose); rc != exitReachedTarget {
+		os.Exit(rc)
+	}
+
+	if *jitter > 0 {
+		d := time.Duration(rand.Int63n(int64(*jitter)))
+		if *verbose {
+			fmt.Printf("jittering for %s\n", d)
+		}
+		time.Sleep(d)
+	}
+	execCmd(cmdArgs)
+}
+
+// waitAndReport wraps waituntil.WaitUntil with our signal handling:
+// SIGHUP cancels the current wait and starts a fresh one (forcing an
+// immediate re-check of the target), while SIGINT and SIGTERM cancel
+// it for good.
+func waitAndReport(tgt time.Time, verbose bool) int {
+	for {
+		ctx, cancel := context.WithCancel(context.Background())
+		sigc := make(chan os.Signal, 1)
+		signal.Notify(sigc, syscall.SIGHUP, syscall.SIGINT, syscall.SIGTERM)
+
+		var logf func(format string, args ...interface{})
+		if verbose {
+			logf = func(format string, args ...interface{}) {
+				fmt.Printf(format+"\n", args...)
+			}
+		}
+
+		done := make(chan error, 1)
+		go func() { done <- waituntil.WaitUntil(ctx, tgt, logf) }()
+
+		select {
+		case <-done:
+			signal.Stop(sigc)
+			cancel()
+			return exitReachedTarget
+		case sig := <-sigc:
+			cancel()
+			<-done
+			signal.Stop(sigc)
+			if sig == syscall.SIGHUP {
+				if verbose {
+					fmt.Println("SIGHUP received, re-checking target")
+				}
+				continue
+			}
+			if verbose {
+				fmt.Printf("%s received, exiting\n", sig)
+			}
+			return exitInterrupted
+		}
+	}
+}
+
+// Run cmdArgs in place of waituntil itself, so that waituntil's exit
+// status becomes the command's exit status. This is the whole point
+// of exec-on-wake mode, so we use syscall.Exec instead of os/exec to
+// actually replace our process rather than fork off a child.
+func execCmd(cmdArgs []string) {
+	path, e := exec.LookPath(cmdArgs[0])
+	if e != nil {
+		fmt.Fprintf(os.Stderr, "%s: cannot find %s: %s\n", os.Args[0], cmdArgs[0], e)
+		os.Exit(127)
+	}
+	e = syscall.Exec(path, cmdArgs, os.Environ())
+	// If we get here, syscall.Exec failed outright (it doesn't
+	// return on success).
+	fmt.Fprintf(os.Stderr, "%s: exec of %s failed: %s\n", os.Args[0], cmdArgs[0], e)
+	os.Exit(126)
+}
@@ -0,0 +1,311 @@
+/*
+Package waituntil parses the flexible time specifications understood
+by the waituntil command, and implements the clock-shift-resilient
+wait loop that sleeps until a target time is reached. It exists so
+that other Go programs can reuse both halves without shelling out to
+the command itself.
+*/
+package waituntil
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/olebedev/when"
+	"github.com/olebedev/when/rules/common"
+	"github.com/olebedev/when/rules/en"
+)
+
+// ErrUnparsableTime is returned by Parse (possibly wrapped) when spec
+// doesn't match any of the time specifications we understand.
+var ErrUnparsableTime = errors.New("cannot parse time argument")
+
+// Our natural-language parser, set up once with the rule sets we
+// want. This is the last thing Parse tries, after everything more
+// specific has failed to match.
+var nlParser = func() *when.Parser {
+	w := when.New(nil)
+	w.Add(en.All...)
+	w.Add(common.All...)
+	return w
+}()
+
+// What our time specifications are missing, if anything.
+// We don't mention missing hours, minutes, or seconds, because
+// we take time.ParseInLocation()'s default zero values for those
+// when parsing full time specifications.
+//
+// (Note that this means we can't use this code to parse 'HH:MM'
+// alone, since that's specified to wrap into tomorrow. That would
+// require a new marker.)
+//
+// The order matters here; things later imply everything before them.
+const (
+	full = iota
+	noyear
+	nomonth
+)
+
+type tSpec struct {
+	spec  string
+	lacks int
+}
+
+// The various time specifications that our full parsing accepts.
+var specs = []tSpec{
+	{"2006-01-02 15:04", full},
+	{"2006-01-02 15:04:05", full},
+	{"2006-01-02", full},
+	{"01-02 15:04", noyear},
+	{"01-02 15:04:05", noyear},
+	{"01-02", noyear},
+	{"02 15:04", nomonth},
+	{"02 15:04:05", nomonth},
+	{"02", nomonth},
+}
+
+// Parse the simple HH:MM[:SS] time specification. This implements
+// rolling over a time in the past into tomorrow.
+func parseHHMM(tspec string, now time.Time) (time.Time, error) {
+	var hr, min, secs int
+	var tgt time.Time
+	_, e := fmt.Sscanf(tspec, "%d:%d:%d", &hr, &min, &secs)
+	if e != nil {
+		secs = 0
+		_, e = fmt.Sscanf(tspec, "%d:%d", &hr, &min)
+		if e != nil {
+			return tgt, e
+		}
+	}
+	// Get the current year, month, day, and location, and create a new
+	// time from it using our hours, minutes, and seconds. There is
+	// probably an easier way to do this.
+	tgt = time.Date(now.Year(), now.Month(), now.Day(), hr, min, secs, 0, now.Location())
+
+	// Before we do anything else: if our target time is right now,
+	// we're done. We accept times that are this minute and with the
+	// target seconds being before now, too, so that '17:01' is
+	// still considered 'right now' at 17:01:33. (And in general
+	// if you say '17:01:30' and hit return at 17:01:35, you
+	// probably don't mean tomorrow. This is arguable.)
+	if now.Hour() == hr && now.Minute() == min && now.Second() >= secs {
+		return tgt, nil
+	}
+
+	// If the target time we've determined is before now, it's actually
+	// tomorrow. Push it forward.
+	if tgt.Before(now) {
+		tgt = tgt.Add(time.Hour * 24)
+	}
+	return tgt, nil
+}
+
+// Casual durations like "7d", "3days", "2hours", "15mins", or "30secs",
+// which time.ParseDuration() doesn't understand on its own.
+var casualDur = regexp.MustCompile(`^(\d+)\s*(d|days?|h|hours?|m|mins?|minutes?|s|secs?|seconds?)$`)
+
+// Parse a relative duration, either one time.ParseDuration() already
+// understands or one of our casual "Nd/Nh/Nm/Ns" forms, and return
+// the resulting target time (now plus the duration).
+func parseDuration(tspec string, now time.Time) (time.Time, error) {
+	d, e := time.ParseDuration(tspec)
+	if e == nil {
+		return now.Add(d), nil
+	}
+
+	m := casualDur.FindStringSubmatch(tspec)
+	if m == nil {
+		return time.Time{}, errors.New("not a relative duration")
+	}
+	n, e := strconv.Atoi(m[1])
+	if e != nil {
+		return time.Time{}, e
+	}
+	var unit time.Duration
+	switch m[2][0] {
+	case 'd':
+		unit = 24 * time.Hour
+	case 'h':
+		unit = time.Hour
+	case 'm':
+		unit = time.Minute
+	case 's':
+		unit = time.Second
+	}
+	return now.Add(time.Duration(n) * unit), nil
+}
+
+// Threshold below which we take a bare integer to be a Unix timestamp
+// in seconds rather than milliseconds.
+const unixMsThreshold = 1e10
+
+// Parse a bare integer as a Unix timestamp, in seconds if it's below
+// unixMsThreshold and in milliseconds otherwise.
+func parseUnix(tspec string) (time.Time, error) {
+	n, e := strconv.ParseInt(tspec, 10, 64)
+	if e != nil {
+		return time.Time{}, e
+	}
+	if n >= unixMsThreshold {
+		return time.UnixMilli(n), nil
+	}
+	return time.Unix(n, 0), nil
+}
+
+// Parse parses a time specification relative to now, which lets
+// callers control what "now" means (for testing, or because they
+// already have a consistent timestamp to work from).
+//
+// spec can be HH:MM[:SS] (rolling over to tomorrow if already past),
+// the full YYYY-MM-DD HH:MM[:SS] form (with YYYY, MM, and the time of
+// day all individually omittable), a relative duration such as "90m"
+// or the casual "7d"/"3days"/"2hours"/"15mins"/"30secs", a bare Unix
+// timestamp in seconds or milliseconds, a full RFC3339 timestamp, or
+// a natural-language expression such as "tomorrow 5pm" or "in 2 hours".
+//
+// If spec doesn't match any of these, Parse returns ErrUnparsableTime.
+func Parse(spec string, now time.Time) (time.Time, error) {
+	t, e := parseHHMM(spec, now)
+	if e == nil {
+		return t, e
+	}
+
+	// This isn't HH:MM[:SS], so we run it through our collection of
+	// time specifications in the hope that something will hit.
+	for _, sp := range specs {
+		t, e = time.ParseInLocation(sp.spec, spec, now.Location())
+		if e != nil {
+			continue
+		}
+		if sp.lacks >= noyear {
+			t = t.AddDate(now.Year(), 0, 0)
+		}
+		if sp.lacks >= nomonth {
+			t = t.AddDate(0, int(now.Month())-1, 0)
+		}
+		return t, e
+	}
+
+	// Not a fixed time either; see if it's a relative duration from now.
+	t, e = parseDuration(spec, now)
+	if e == nil {
+		return t, e
+	}
+
+	// See if it's a bare Unix timestamp, or a full RFC3339 timestamp.
+	t, e = parseUnix(spec)
+	if e == nil {
+		return t, e
+	}
+	t, e = time.Parse(time.RFC3339, spec)
+	if e == nil {
+		return t, e
+	}
+
+	// Last resort: see if it's a natural-language expression like
+	// "tomorrow 5pm" or "in 2 hours".
+	r, e := nlParser.Parse(spec, now)
+	if e == nil && r != nil {
+		return r.Time, nil
+	}
+	return time.Time{}, ErrUnparsableTime
+}
+
+// How far apart the monotonic clock and the wall clock are allowed to
+// drift across a sleep before we consider it a suspend/resume or an
+// NTP slew and cap the next sleep down, so the divergence gets
+// noticed promptly instead of on the next already-short sleep.
+const clockSlewThreshold = 2 * time.Second
+
+// nextSleep picks how long to sleep before the next check of the
+// target, given how much time remains (dur) and how far the wall
+// clock has drifted from the monotonic clock since the last check
+// (drift). It also reports whether it capped dur because of a clock
+// slew, so callers can log that if they care. It's split out from
+// WaitUntil so the capping rules, including the clock-slew case, can
+// be tested without an actual sleep.
+func nextSleep(dur, drift time.Duration) (time.Duration, bool) {
+	// If the monotonic and wall clocks have diverged since our last
+	// check (eg a suspend/resume or an NTP step), our usual sleep
+	// caps below may be stale, so sleep no longer than
+	// clockSlewThreshold to re-check promptly.
+	if drift > clockSlewThreshold || drift < -clockSlewThreshold {
+		return clockSlewThreshold, true
+	}
+
+	// If our target time is within a minute, we sleep for exactly
+	// that long on the assumption that clock changes over that short
+	// a time are unimportant. Within 10 minutes of the target we cap
+	// sleeps at 60s so a clock shift is noticed reasonably promptly.
+	// Otherwise, we sleep for half the time or an hour, whichever is
+	// smaller.
+	switch {
+	case dur > (2 * time.Hour):
+		return time.Hour, false
+	case dur > (10 * time.Minute):
+		return dur / 2, false
+	case dur > time.Minute:
+		return 60 * time.Second, false
+	}
+	return dur, false
+}
+
+// WaitUntil blocks until target is reached, sleeping in installments
+// so that clock changes get noticed and don't make it overshoot (or
+// undershoot) badly. Before each sleep it compares how much monotonic
+// time passed since its last check to how much wall-clock time
+// passed; a long gap between the two (eg the machine was suspended)
+// makes it shorten the upcoming sleep so it re-checks promptly. The
+// anchors for that comparison are reset on every iteration, so a
+// one-time clock shift only affects the sleep immediately after it,
+// not every sleep for the rest of the wait.
+//
+// If logf is non-nil, WaitUntil calls it once each time it detects a
+// clock slew, so callers can report it (eg under -v); logf is never
+// called otherwise. Pass nil if you don't care.
+//
+// WaitUntil returns nil once target is reached, or ctx.Err() if ctx
+// is cancelled first.
+func WaitUntil(ctx context.Context, target time.Time, logf func(format string, args ...interface{})) error {
+	start := time.Now()
+	startWall := start.Round(0)
+
+	for {
+		now := time.Now()
+		if now.After(target) {
+			return nil
+		}
+		dur := target.Sub(now)
+
+		// we have a one-second granularity; if we're closer
+		// than that, we're done.
+		if dur < time.Second {
+			return nil
+		}
+
+		drift := now.Round(0).Sub(startWall) - time.Since(start)
+		var slewed bool
+		dur, slewed = nextSleep(dur, drift)
+		if slewed && logf != nil {
+			logf("clock drift detected (%s), re-checking", drift)
+		}
+
+		// Reset our anchors so the next iteration's drift is
+		// measured since this check, not since WaitUntil started.
+		start = now
+		startWall = now.Round(0)
+
+		timer := time.NewTimer(dur)
+		select {
+		case <-timer.C:
+			// normal wake-up; loop around and re-check.
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package waituntil
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	now := time.Date(2025, time.June, 15, 12, 0, 0, 0, time.UTC)
+
+	cases := []struct {
+		spec string
+		want time.Time
+	}{
+		{"13:00", time.Date(2025, time.June, 15, 13, 0, 0, 0, time.UTC)},
+		{"11:00", time.Date(2025, time.June, 16, 11, 0, 0, 0, time.UTC)},
+		{"2025-07-04", time.Date(2025, time.July, 4, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, c := range cases {
+		got, e := Parse(c.spec, now)
+		if e != nil {
+			t.Errorf("Parse(%q, ...) returned error: %s", c.spec, e)
+			continue
+		}
+		if !got.Equal(c.want) {
+			t.Errorf("Parse(%q, ...) = %s, want %s", c.spec, got, c.want)
+		}
+	}
+}
+
+func TestParseDuration(t *testing.T) {
+	now := time.Date(2025, time.June, 15, 12, 0, 0, 0, time.UTC)
+
+	cases := []struct {
+		spec string
+		want time.Time
+	}{
+		{"90m", now.Add(90 * time.Minute)},
+		{"2h30m", now.Add(2*time.Hour + 30*time.Minute)},
+		{"45s", now.Add(45 * time.Second)},
+		{"7d", now.Add(7 * 24 * time.Hour)},
+		{"3days", now.Add(3 * 24 * time.Hour)},
+		{"2hours", now.Add(2 * time.Hour)},
+		{"15mins", now.Add(15 * time.Minute)},
+		{"30secs", now.Add(30 * time.Second)},
+	}
+	for _, c := range cases {
+		got, e := Parse(c.spec, now)
+		if e != nil {
+			t.Errorf("Parse(%q, ...) returned error: %s", c.spec, e)
+			continue
+		}
+		if !got.Equal(c.want) {
+			t.Errorf("Parse(%q, ...) = %s, want %s", c.spec, got, c.want)
+		}
+	}
+}
+
+func TestParseUnixAndRFC3339(t *testing.T) {
+	now := time.Date(2025, time.June, 15, 12, 0, 0, 0, time.UTC)
+
+	cases := []struct {
+		spec string
+		want time.Time
+	}{
+		{"1750000000", time.Unix(1750000000, 0)},
+		{"1750000000000", time.UnixMilli(1750000000000)},
+		{"2025-07-04T09:00:00Z", time.Date(2025, time.July, 4, 9, 0, 0, 0, time.UTC)},
+		{"2025-07-04T09:00:00-05:00", time.Date(2025, time.July, 4, 9, 0, 0, 0, time.FixedZone("", -5*60*60))},
+	}
+	for _, c := range cases {
+		got, e := Parse(c.spec, now)
+		if e != nil {
+			t.Errorf("Parse(%q, ...) returned error: %s", c.spec, e)
+			continue
+		}
+		if !got.Equal(c.want) {
+			t.Errorf("Parse(%q, ...) = %s, want %s", c.spec, got, c.want)
+		}
+	}
+}
+
+func TestParseNaturalLanguage(t *testing.T) {
+	// A Sunday, so "tomorrow"/"monday"/"next friday" are unambiguous.
+	now := time.Date(2025, time.June, 15, 12, 0, 0, 0, time.UTC)
+
+	cases := []struct {
+		spec string
+		want time.Time
+	}{
+		{"tomorrow 5pm", time.Date(2025, time.June, 16, 17, 0, 0, 0, time.UTC)},
+		{"in 2 hours", time.Date(2025, time.June, 15, 14, 0, 0, 0, time.UTC)},
+		{"next friday 14:00", time.Date(2025, time.June, 20, 14, 0, 0, 0, time.UTC)},
+		{"monday 9am", time.Date(2025, time.June, 16, 9, 0, 0, 0, time.UTC)},
+	}
+	for _, c := range cases {
+		got, e := Parse(c.spec, now)
+		if e != nil {
+			t.Errorf("Parse(%q, ...) returned error: %s", c.spec, e)
+			continue
+		}
+		if !got.Equal(c.want) {
+			t.Errorf("Parse(%q, ...) = %s, want %s", c.spec, got, c.want)
+		}
+	}
+}
+
+func TestParseUnparsable(t *testing.T) {
+	now := time.Date(2025, time.June, 15, 12, 0, 0, 0, time.UTC)
+	_, e := Parse("not a time at all", now)
+	if e != ErrUnparsableTime {
+		t.Errorf("Parse of garbage = %v, want ErrUnparsableTime", e)
+	}
+}
+
+func TestWaitUntilPast(t *testing.T) {
+	if e := WaitUntil(context.Background(), time.Now().Add(-time.Hour), nil); e != nil {
+		t.Errorf("WaitUntil of a past target returned %v, want nil", e)
+	}
+}
+
+func TestWaitUntilLogsDrift(t *testing.T) {
+	var msgs []string
+	logf := func(format string, args ...interface{}) {
+		msgs = append(msgs, fmt.Sprintf(format, args...))
+	}
+
+	// A target far enough out that the first iteration sleeps instead
+	// of returning immediately, so WaitUntil gets a chance to measure
+	// drift (there is none here) and call logf, or not.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	cancel()
+	if e := WaitUntil(ctx, time.Now().Add(time.Hour), logf); e != context.Canceled {
+		t.Errorf("WaitUntil returned %v, want context.Canceled", e)
+	}
+	if len(msgs) != 0 {
+		t.Errorf("WaitUntil called logf with no drift present: %v", msgs)
+	}
+}
+
+func TestNextSleep(t *testing.T) {
+	cases := []struct {
+		dur, drift time.Duration
+		want       time.Duration
+		wantSlewed bool
+	}{
+		// No drift: the usual tiering applies.
+		{3 * time.Hour, 0, time.Hour, false},
+		{20 * time.Minute, 0, 10 * time.Minute, false},
+		{5 * time.Minute, 0, 60 * time.Second, false},
+		{30 * time.Second, 0, 30 * time.Second, false},
+		// A single suspend/NTP step should cap the very next sleep,
+		// and be reported so the caller can log it...
+		{10 * 24 * time.Hour, 5 * time.Minute, clockSlewThreshold, true},
+		{10 * 24 * time.Hour, -5 * time.Minute, clockSlewThreshold, true},
+		// ...but once drift is back under the threshold (as it will be
+		// on the next iteration, since the anchors get reset), normal
+		// tiering resumes even for a sleep that's still days long.
+		{10 * 24 * time.Hour, time.Second, time.Hour, false},
+	}
+	for _, c := range cases {
+		got, slewed := nextSleep(c.dur, c.drift)
+		if got != c.want || slewed != c.wantSlewed {
+			t.Errorf("nextSleep(%s, %s) = (%s, %v), want (%s, %v)", c.dur, c.drift, got, slewed, c.want, c.wantSlewed)
+		}
+	}
+}